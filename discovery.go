@@ -0,0 +1,303 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/walkon/gnet/internal/logging"
+)
+
+const defaultEtcdKeyPrefix = "/gnet/services"
+
+// loadStatsKeySuffix marks the child key a registrar publishes its
+// per-loop connection counts under. Resolver.Watch filters out keys ending
+// in this suffix so load-stats blobs (map[int]int, not a ServiceInfo) never
+// get unmarshalled into a bogus, all-zero endpoint.
+const loadStatsKeySuffix = "/load"
+
+// ServiceInfo is the payload a running server publishes to etcd so that a
+// Resolver-based client can discover it.
+type ServiceInfo struct {
+	Addr         string  `json:"addr"`
+	Network      string  `json:"network"`
+	NumEventLoop int     `json:"numEventLoop"`
+	ReusePort    bool    `json:"reusePort"`
+	LoadAvg      float64 `json:"loadAvg"`
+}
+
+// registrar publishes a server's ServiceInfo under a TTL lease and keeps
+// that lease alive for as long as the server runs, so a crashed instance
+// auto-deregisters once the lease expires.
+type registrar struct {
+	cli     *clientv3.Client
+	key     string
+	ttl     int64
+	leaseID clientv3.LeaseID
+	info    ServiceInfo
+}
+
+func newRegistrar(opts *Options, key string) (*registrar, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   opts.EtcdEndpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ttl := opts.EtcdLeaseTTL
+	if ttl <= 0 {
+		ttl = 10
+	}
+	return &registrar{cli: cli, key: key, ttl: ttl}, nil
+}
+
+func (r *registrar) register(ctx context.Context, info ServiceInfo) error {
+	lease, err := r.cli.Grant(ctx, r.ttl)
+	if err != nil {
+		return err
+	}
+	r.leaseID = lease.ID
+	r.info = info
+
+	return r.putInfo(ctx)
+}
+
+// putInfo (re-)publishes r.info under r.key with r.leaseID, e.g. after
+// publishLoadStats updates r.info.LoadAvg.
+func (r *registrar) putInfo(ctx context.Context) error {
+	payload, err := json.Marshal(r.info)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.cli.Put(ctx, r.key, string(payload), clientv3.WithLease(r.leaseID))
+	return err
+}
+
+// keepAlive renews the lease until ctx is cancelled. A flaky etcd only gets
+// logged, never allowed to block or crash the server.
+func (r *registrar) keepAlive(ctx context.Context) {
+	ch, err := r.cli.KeepAlive(ctx, r.leaseID)
+	if err != nil {
+		logging.Errorf("gnet: etcd keepalive failed, service registration will expire: %v", err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				logging.Errorf("gnet: etcd keepalive channel closed, service registration will expire")
+				return
+			}
+		}
+	}
+}
+
+func (r *registrar) close() error {
+	return r.cli.Close()
+}
+
+// publishLoadStats periodically writes each event-loop's live connection
+// count to etcd under r.key+loadStatsKeySuffix, for tooling that wants the
+// raw per-loop breakdown, and republishes r.info with LoadAvg set to the
+// mean connection count across loops, so a plain Resolver.Watch caller can
+// compare instances by load without parsing the per-loop key itself. It
+// only runs when Options.LB is LeastConnections.
+func (svr *server) publishLoadStats(ctx context.Context, r *registrar, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			counts := make(map[int]int)
+			svr.lb.iterate(func(i int, el *eventloop) bool {
+				counts[i] = len(el.connections)
+				return true
+			})
+
+			payload, err := json.Marshal(counts)
+			if err != nil {
+				continue
+			}
+
+			putCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+			_, err = r.cli.Put(putCtx, r.key+loadStatsKeySuffix, string(payload), clientv3.WithLease(r.leaseID))
+			cancel()
+			if err != nil {
+				logging.LogErr(fmt.Errorf("gnet: failed to publish load stats to etcd: %w", err))
+				continue
+			}
+
+			r.info.LoadAvg = meanLoad(counts)
+			putCtx, cancel = context.WithTimeout(ctx, 3*time.Second)
+			err = r.putInfo(putCtx)
+			cancel()
+			if err != nil {
+				logging.LogErr(fmt.Errorf("gnet: failed to publish loadAvg to etcd: %w", err))
+			}
+		}
+	}
+}
+
+// meanLoad returns the average connection count across counts, or 0 for an
+// empty map.
+func meanLoad(counts map[int]int) float64 {
+	if len(counts) == 0 {
+		return 0
+	}
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return float64(total) / float64(len(counts))
+}
+
+// startServiceDiscovery registers svr into etcd and keeps the registration
+// alive until svr shuts down. It is started from serve() as a goroutine and
+// degrades cleanly: any etcd error is logged and this function returns,
+// never blocking svr.start.
+func (svr *server) startServiceDiscovery(opts *Options, ln *listener, numEventLoop int) {
+	prefix := opts.EtcdKeyPrefix
+	if prefix == "" {
+		prefix = defaultEtcdKeyPrefix
+	}
+	key := fmt.Sprintf("%s/%s", prefix, ln.lnaddr.String())
+
+	r, err := newRegistrar(opts, key)
+	if err != nil {
+		logging.Errorf("gnet: etcd service registration disabled, failed to connect: %v", err)
+		return
+	}
+
+	info := ServiceInfo{
+		Addr:         ln.lnaddr.String(),
+		Network:      ln.network,
+		NumEventLoop: numEventLoop,
+		ReusePort:    opts.ReusePort,
+	}
+
+	regCtx, cancel := context.WithTimeout(svr.discoveryCtx, 5*time.Second)
+	err = r.register(regCtx, info)
+	cancel()
+	if err != nil {
+		logging.Errorf("gnet: etcd service registration failed: %v", err)
+		_ = r.close()
+		return
+	}
+
+	go r.keepAlive(svr.discoveryCtx)
+
+	if opts.LB == LeastConnections {
+		go svr.publishLoadStats(svr.discoveryCtx, r, 5*time.Second)
+	}
+
+	<-svr.discoveryCtx.Done()
+	_ = r.close()
+}
+
+// Resolver watches an etcd key prefix populated by gnet servers and streams
+// the set of currently-registered endpoints, so a client can keep an
+// AddTCPConnector-based connection pool pointed at healthy instances
+// without any external load-balancer configuration.
+type Resolver struct {
+	cli    *clientv3.Client
+	prefix string
+}
+
+// NewResolver creates a Resolver backed by the given etcd endpoints,
+// watching keys under prefix.
+func NewResolver(endpoints []string, prefix string) (*Resolver, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{cli: cli, prefix: prefix}, nil
+}
+
+// parseServiceInfo decodes an etcd key/value pair observed under a
+// Resolver's prefix into a ServiceInfo, reporting false for a registrar's
+// own per-loop load-stats key (see loadStatsKeySuffix) or a value that
+// isn't valid ServiceInfo JSON.
+func parseServiceInfo(key, value []byte) (ServiceInfo, bool) {
+	if bytes.HasSuffix(key, []byte(loadStatsKeySuffix)) {
+		return ServiceInfo{}, false
+	}
+	var info ServiceInfo
+	if err := json.Unmarshal(value, &info); err != nil {
+		return ServiceInfo{}, false
+	}
+	return info, true
+}
+
+// Watch calls onUpdate once with the resolver's current endpoints and again
+// every time etcd reports a change under its prefix, until ctx is
+// cancelled.
+func (r *Resolver) Watch(ctx context.Context, onUpdate func([]ServiceInfo)) error {
+	emit := func() error {
+		resp, err := r.cli.Get(ctx, r.prefix, clientv3.WithPrefix())
+		if err != nil {
+			return err
+		}
+		infos := make([]ServiceInfo, 0, len(resp.Kvs))
+		for _, kv := range resp.Kvs {
+			if info, ok := parseServiceInfo(kv.Key, kv.Value); ok {
+				infos = append(infos, info)
+			}
+		}
+		onUpdate(infos)
+		return nil
+	}
+
+	if err := emit(); err != nil {
+		return err
+	}
+
+	watchCh := r.cli.Watch(ctx, r.prefix, clientv3.WithPrefix())
+	go func() {
+		for range watchCh {
+			if err := emit(); err != nil {
+				logging.Errorf("gnet: resolver failed to refresh endpoints: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close releases the resolver's etcd client.
+func (r *Resolver) Close() error {
+	return r.cli.Close()
+}
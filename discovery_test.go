@@ -0,0 +1,78 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import "testing"
+
+func TestParseServiceInfoSkipsLoadStatsKeys(t *testing.T) {
+	key := []byte("/gnet/services/127.0.0.1:9000" + loadStatsKeySuffix)
+	value := []byte(`{"0":3,"1":5}`)
+
+	info, ok := parseServiceInfo(key, value)
+	if ok {
+		t.Errorf("parseServiceInfo(%q) = (%+v, true), want ok = false", key, info)
+	}
+}
+
+func TestParseServiceInfoDecodesServiceKey(t *testing.T) {
+	key := []byte("/gnet/services/127.0.0.1:9000")
+	value := []byte(`{"addr":"127.0.0.1:9000","network":"tcp","numEventLoop":4,"reusePort":true,"loadAvg":1.5}`)
+
+	info, ok := parseServiceInfo(key, value)
+	if !ok {
+		t.Fatalf("parseServiceInfo(%q) ok = false, want true", key)
+	}
+
+	want := ServiceInfo{Addr: "127.0.0.1:9000", Network: "tcp", NumEventLoop: 4, ReusePort: true, LoadAvg: 1.5}
+	if info != want {
+		t.Errorf("parseServiceInfo(%q) = %+v, want %+v", key, info, want)
+	}
+}
+
+func TestParseServiceInfoRejectsInvalidJSON(t *testing.T) {
+	key := []byte("/gnet/services/127.0.0.1:9000")
+	value := []byte("not json")
+
+	if _, ok := parseServiceInfo(key, value); ok {
+		t.Errorf("parseServiceInfo(%q) ok = true, want false for invalid JSON", key)
+	}
+}
+
+func TestMeanLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[int]int
+		want   float64
+	}{
+		{name: "empty", counts: map[int]int{}, want: 0},
+		{name: "single", counts: map[int]int{0: 4}, want: 4},
+		{name: "multiple", counts: map[int]int{0: 2, 1: 4}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := meanLoad(tt.counts); got != tt.want {
+				t.Errorf("meanLoad(%v) = %v, want %v", tt.counts, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import "time"
+
+// EventType identifies the kind of structured lifecycle event emitted by an
+// EventLogger.
+type EventType int
+
+const (
+	// EventServerStarted fires once, after all event-loops are up and the
+	// listener is accepting connections.
+	EventServerStarted EventType = iota
+	// EventServerShutdown fires once, after OnShutdown has been called and
+	// all event-loops are being torn down.
+	EventServerShutdown
+	// EventConnOpen fires when the kqueue event-loop (FreeBSD, DragonFly,
+	// Darwin) accepts a connection. The Linux event-loop does not emit it,
+	// and neither do connections added through AddTCPConnector.
+	EventConnOpen
+	// EventConnClose fires when the kqueue event-loop (FreeBSD, DragonFly,
+	// Darwin) closes a connection on an EVFilterSock event. Connections
+	// closed on any other path, and all connections on Linux, emit none.
+	EventConnClose
+	// EventTick is reserved for the per-invocation ticker event, fired when
+	// Options.Ticker is enabled. No code path emits it yet.
+	EventTick
+	// EventLoopError fires when setting up the event-loops fails: entering
+	// the network namespace, binding a listener or opening a poller. Errors
+	// a running event-loop's poller returns are not reported as events.
+	EventLoopError
+)
+
+// String returns the event's machine-readable name. It doubles as the JSON
+// "event" field and the GNET_EVENT journald field.
+func (t EventType) String() string {
+	switch t {
+	case EventServerStarted:
+		return "server_started"
+	case EventServerShutdown:
+		return "server_shutdown"
+	case EventConnOpen:
+		return "conn_open"
+	case EventConnClose:
+		return "conn_close"
+	case EventTick:
+		return "tick"
+	case EventLoopError:
+		return "loop_error"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a single structured connection-lifecycle event. Not every field
+// is meaningful for every EventType: LocalAddr/RemoteAddr/FD, for instance,
+// are only meaningful for the per-connection events. BytesIn, BytesOut and
+// Duration are best-effort: they are only ever set on EventConnClose, and
+// only to the extent the event source tracks that information for the
+// connection being closed -- a zero value does not necessarily mean nothing
+// was transferred.
+type Event struct {
+	Type       EventType
+	Time       time.Time
+	LoopIndex  int
+	LocalAddr  string
+	RemoteAddr string
+	FD         int
+	BytesIn    int
+	BytesOut   int
+	Duration   time.Duration
+	Err        error
+}
+
+// EventLogger is the interface gnet drives to emit structured connection
+// lifecycle events. It plays the same role for observability that ICodec
+// plays for framing: callers pick an implementation through
+// Options.EventLogger and gnet calls into it from serve(), from event-loop
+// setup and shutdown, and from the kqueue event-loop as connections come
+// and go (see EventConnOpen and EventConnClose).
+//
+// Implementations must be safe for concurrent use, since every event-loop
+// goroutine logs to the same EventLogger, and should not block the caller
+// for more than a negligible amount of time; slow sinks should buffer or
+// drop rather than stall an event-loop.
+type EventLogger interface {
+	// Log emits e.
+	Log(e Event)
+
+	// Close flushes and releases any resources held by the logger. gnet
+	// calls it once, during server shutdown.
+	Close() error
+}
+
+// NullEventLogger discards every event. It is the default
+// Options.EventLogger, so enabling observability is strictly opt-in.
+type NullEventLogger struct{}
+
+// Log implements EventLogger.
+func (NullEventLogger) Log(Event) {}
+
+// Close implements EventLogger.
+func (NullEventLogger) Close() error { return nil }
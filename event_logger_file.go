@@ -0,0 +1,151 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileEventLogger is an EventLogger that appends newline-delimited JSON
+// records to a file, rotating it to path+".1" once it grows past maxBytes.
+type FileEventLogger struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+// NewFileEventLogger opens (creating if necessary) path for appending and
+// returns a FileEventLogger that rotates once the file exceeds maxBytes. A
+// maxBytes of 0 disables rotation.
+func NewFileEventLogger(path string, maxBytes int64) (*FileEventLogger, error) {
+	l := &FileEventLogger{path: path, maxBytes: maxBytes}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *FileEventLogger) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("gnet: failed to open event log file %q: %w", l.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// jsonEvent is the on-disk shape of an Event; zero-value fields are omitted
+// so each line stays short for the common events.
+type jsonEvent struct {
+	Event      string  `json:"event"`
+	Time       string  `json:"time"`
+	LoopIndex  int     `json:"loop_index,omitempty"`
+	LocalAddr  string  `json:"local_addr,omitempty"`
+	RemoteAddr string  `json:"remote_addr,omitempty"`
+	FD         int     `json:"fd,omitempty"`
+	BytesIn    int     `json:"bytes_in,omitempty"`
+	BytesOut   int     `json:"bytes_out,omitempty"`
+	DurationMS float64 `json:"duration_ms,omitempty"`
+	Err        string  `json:"error,omitempty"`
+}
+
+// Log implements EventLogger.
+func (l *FileEventLogger) Log(e Event) {
+	rec := jsonEvent{
+		Event:      e.Type.String(),
+		Time:       e.Time.Format(time.RFC3339Nano),
+		LoopIndex:  e.LoopIndex,
+		LocalAddr:  e.LocalAddr,
+		RemoteAddr: e.RemoteAddr,
+		FD:         e.FD,
+		BytesIn:    e.BytesIn,
+		BytesOut:   e.BytesOut,
+		DurationMS: float64(e.Duration.Microseconds()) / 1000,
+	}
+	if e.Err != nil {
+		rec.Err = e.Err.Error()
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxBytes > 0 && l.size+int64(len(line)) > l.maxBytes {
+		// A failed rotation is not fatal: rotateLocked leaves l.file as
+		// whichever handle is still valid, so Log keeps writing somewhere
+		// sane either way.
+		_ = l.rotateLocked()
+	}
+	n, err := l.file.Write(line)
+	if err == nil {
+		l.size += int64(n)
+	}
+}
+
+// rotateLocked renames the current file to path+".1" and opens a fresh
+// file at path. It renames before closing the old handle, so a failed
+// rename (cross-device path, permission error, concurrent removal of the
+// parent directory) leaves l.file exactly as it was: open and writable.
+// If the rename succeeds but the new file can't be opened, l.file falls
+// back to the old (now renamed, but still open) handle rather than being
+// left closed.
+func (l *FileEventLogger) rotateLocked() error {
+	old := l.file
+
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+
+	if err := l.open(); err != nil {
+		l.file = old
+		if info, statErr := old.Stat(); statErr == nil {
+			l.size = info.Size()
+		}
+		return err
+	}
+
+	return old.Close()
+}
+
+// Close implements EventLogger.
+func (l *FileEventLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
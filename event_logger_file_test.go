@@ -0,0 +1,158 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package gnet
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileEventLoggerEncodesEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	l, err := NewFileEventLogger(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileEventLogger() error = %v", err)
+	}
+	defer l.Close()
+
+	l.Log(Event{
+		Type:       EventConnClose,
+		Time:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		LoopIndex:  3,
+		RemoteAddr: "10.0.0.1:4242",
+		FD:         7,
+		BytesIn:    128,
+		BytesOut:   256,
+		Duration:   1500 * time.Microsecond,
+		Err:        errors.New("boom"),
+	})
+
+	line := readLastLine(t, path)
+
+	var rec jsonEvent
+	if err := json.Unmarshal(line, &rec); err != nil {
+		t.Fatalf("json.Unmarshal(%q) error = %v", line, err)
+	}
+
+	if rec.Event != "conn_close" {
+		t.Errorf("Event = %q, want %q", rec.Event, "conn_close")
+	}
+	if rec.LoopIndex != 3 {
+		t.Errorf("LoopIndex = %d, want 3", rec.LoopIndex)
+	}
+	if rec.RemoteAddr != "10.0.0.1:4242" {
+		t.Errorf("RemoteAddr = %q, want %q", rec.RemoteAddr, "10.0.0.1:4242")
+	}
+	if rec.FD != 7 {
+		t.Errorf("FD = %d, want 7", rec.FD)
+	}
+	if rec.BytesIn != 128 || rec.BytesOut != 256 {
+		t.Errorf("BytesIn/BytesOut = %d/%d, want 128/256", rec.BytesIn, rec.BytesOut)
+	}
+	if rec.DurationMS != 1.5 {
+		t.Errorf("DurationMS = %v, want 1.5", rec.DurationMS)
+	}
+	if rec.Err != "boom" {
+		t.Errorf("Err = %q, want %q", rec.Err, "boom")
+	}
+}
+
+func TestFileEventLoggerRotatesPastMaxBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	l, err := NewFileEventLogger(path, 64)
+	if err != nil {
+		t.Fatalf("NewFileEventLogger() error = %v", err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 20; i++ {
+		l.Log(Event{Type: EventConnOpen, Time: time.Now(), FD: i})
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("expected rotated file %s to exist, stat error = %v", path+".1", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("os.Stat(%q) error = %v", path, err)
+	}
+	if info.Size() > 64*2 {
+		t.Errorf("current log file size = %d, expected it to have been rotated down below ~2x maxBytes", info.Size())
+	}
+}
+
+func TestFileEventLoggerSurvivesFailedRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.log")
+	l, err := NewFileEventLogger(path, 8)
+	if err != nil {
+		t.Fatalf("NewFileEventLogger() error = %v", err)
+	}
+	defer l.Close()
+
+	// rename(2) refuses to replace an existing directory regardless of
+	// permissions (even for root), so this reliably forces rotateLocked's
+	// os.Rename to fail without relying on a permission check that an
+	// unprivileged-only test would need.
+	if err := os.Mkdir(path+".1", 0755); err != nil {
+		t.Fatalf("os.Mkdir() error = %v", err)
+	}
+
+	l.Log(Event{Type: EventConnOpen, Time: time.Now(), FD: 1})
+	l.Log(Event{Type: EventConnOpen, Time: time.Now(), FD: 2})
+
+	if l.file == nil {
+		t.Fatal("l.file is nil after a failed rotation, want the pre-rotation handle kept open")
+	}
+	if _, err := l.file.Write([]byte("{}\n")); err != nil {
+		t.Errorf("l.file.Write() after failed rotation error = %v, want writes to keep succeeding", err)
+	}
+}
+
+func readLastLine(t *testing.T, path string) []byte {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("os.Open(%q) error = %v", path, err)
+	}
+	defer f.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		last = append([]byte(nil), scanner.Bytes()...)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scanner error = %v", err)
+	}
+	if last == nil {
+		t.Fatalf("no lines found in %q", path)
+	}
+	return last
+}
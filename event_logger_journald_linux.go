@@ -0,0 +1,143 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build linux
+
+package gnet
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const journalSocket = "/run/systemd/journal/socket"
+
+// gnetEventsMessageID is a fixed journald MESSAGE_ID identifying gnet
+// lifecycle events, so `journalctl MESSAGE_ID=...` can isolate them from an
+// application's own log lines.
+const gnetEventsMessageID = "b6b8a5c6c2f84a0ab76e1a7a7c5d8f1e"
+
+// JournaldEventLogger is an EventLogger that writes each event straight to
+// the systemd journal, using the same datagram wire format sd_journal_send
+// uses, so no cgo is required. Every event carries MESSAGE, MESSAGE_ID,
+// PRIORITY and a GNET_* field per populated Event member, queryable with
+// e.g. `journalctl GNET_EVENT=server_started`.
+type JournaldEventLogger struct {
+	conn *net.UnixConn
+}
+
+// NewJournaldEventLogger dials the local systemd journal socket.
+func NewJournaldEventLogger() (*JournaldEventLogger, error) {
+	raddr, err := net.ResolveUnixAddr("unixgram", journalSocket)
+	if err != nil {
+		return nil, fmt.Errorf("gnet: failed to resolve journal socket: %w", err)
+	}
+	conn, err := net.DialUnix("unixgram", nil, raddr)
+	if err != nil {
+		return nil, fmt.Errorf("gnet: failed to dial journal socket: %w", err)
+	}
+	return &JournaldEventLogger{conn: conn}, nil
+}
+
+// Log implements EventLogger.
+func (l *JournaldEventLogger) Log(e Event) {
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", journalMessage(e))
+	writeJournalField(&buf, "MESSAGE_ID", gnetEventsMessageID)
+	writeJournalField(&buf, "PRIORITY", strconv.Itoa(journalPriority(e)))
+	writeJournalField(&buf, "GNET_EVENT", e.Type.String())
+	writeJournalField(&buf, "GNET_LOOP_INDEX", strconv.Itoa(e.LoopIndex))
+	if e.LocalAddr != "" {
+		writeJournalField(&buf, "GNET_LOCAL_ADDR", e.LocalAddr)
+	}
+	if e.RemoteAddr != "" {
+		writeJournalField(&buf, "GNET_REMOTE_ADDR", e.RemoteAddr)
+	}
+	if e.FD != 0 {
+		writeJournalField(&buf, "GNET_FD", strconv.Itoa(e.FD))
+	}
+	if e.BytesIn != 0 {
+		writeJournalField(&buf, "GNET_BYTES_IN", strconv.Itoa(e.BytesIn))
+	}
+	if e.BytesOut != 0 {
+		writeJournalField(&buf, "GNET_BYTES_OUT", strconv.Itoa(e.BytesOut))
+	}
+	if e.Duration != 0 {
+		writeJournalField(&buf, "GNET_DURATION_US", strconv.FormatInt(e.Duration.Microseconds(), 10))
+	}
+	if e.Err != nil {
+		writeJournalField(&buf, "GNET_ERROR", e.Err.Error())
+	}
+
+	// The journal is best-effort observability: a write failure here must
+	// never propagate into the event-loop's hot path.
+	_, _ = l.conn.Write(buf.Bytes())
+}
+
+// writeJournalField appends one journal-export-format field to buf:
+// KEY=VALUE\n for single-line values, or the length-prefixed binary form
+// when value contains a newline, per systemd.journal-fields(7).
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	if bytes.ContainsRune([]byte(value), '\n') {
+		buf.WriteString(key)
+		buf.WriteByte('\n')
+		var lenBuf [8]byte
+		n := uint64(len(value))
+		for i := 0; i < 8; i++ {
+			lenBuf[i] = byte(n >> (8 * uint(i)))
+		}
+		buf.Write(lenBuf[:])
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+func journalMessage(e Event) string {
+	switch e.Type {
+	case EventConnOpen:
+		return fmt.Sprintf("connection opened: fd=%d remote=%s", e.FD, e.RemoteAddr)
+	case EventConnClose:
+		return fmt.Sprintf("connection closed: fd=%d remote=%s in=%d out=%d dur=%s", e.FD, e.RemoteAddr, e.BytesIn, e.BytesOut, e.Duration)
+	case EventLoopError:
+		return fmt.Sprintf("event-loop %d error: %v", e.LoopIndex, e.Err)
+	default:
+		return e.Type.String()
+	}
+}
+
+func journalPriority(e Event) int {
+	if e.Type == EventLoopError {
+		return 3 // LOG_ERR
+	}
+	return 6 // LOG_INFO
+}
+
+// Close implements EventLogger.
+func (l *JournaldEventLogger) Close() error {
+	return l.conn.Close()
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build !linux
+
+package gnet
+
+import "errors"
+
+// JournaldEventLogger is unavailable outside Linux; NewJournaldEventLogger
+// always fails so callers fall back to FileEventLogger or NullEventLogger.
+type JournaldEventLogger struct{}
+
+// NewJournaldEventLogger returns an error on non-Linux platforms.
+func NewJournaldEventLogger() (*JournaldEventLogger, error) {
+	return nil, errors.New("gnet: JournaldEventLogger is only supported on linux")
+}
+
+// Log implements EventLogger.
+func (*JournaldEventLogger) Log(Event) {}
+
+// Close implements EventLogger.
+func (*JournaldEventLogger) Close() error { return nil }
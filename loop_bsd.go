@@ -22,13 +22,35 @@
 
 package gnet
 
-import "github.com/walkon/gnet/internal/netpoll"
+import (
+	"time"
+
+	"github.com/walkon/gnet/internal/netpoll"
+)
 
 func (el *eventloop) handleEvent(fd int, filter int16) (err error) {
 	if c, ok := el.connections[fd]; ok {
 		switch filter {
 		case netpoll.EVFilterSock:
+			remoteAddr := ""
+			if ra := c.RemoteAddr(); ra != nil {
+				remoteAddr = ra.String()
+			}
+			var duration time.Duration
+			if openedAt, ok := el.connOpenedAt[fd]; ok {
+				duration = time.Since(openedAt)
+				delete(el.connOpenedAt, fd)
+			}
 			err = el.loopCloseConn(c, nil)
+			el.eventLogger.Log(Event{
+				Type:       EventConnClose,
+				Time:       time.Now(),
+				LoopIndex:  el.idx,
+				RemoteAddr: remoteAddr,
+				FD:         fd,
+				Duration:   duration,
+				Err:        err,
+			})
 		case netpoll.EVFilterWrite:
 			err = el.loopWrite(c)
 		case netpoll.EVFilterRead:
@@ -36,5 +58,22 @@ func (el *eventloop) handleEvent(fd int, filter int16) (err error) {
 		}
 		return
 	}
-	return el.loopAccept(fd)
+
+	if err = el.loopAccept(fd); err == nil {
+		remoteAddr := ""
+		if c, ok := el.connections[fd]; ok {
+			if ra := c.RemoteAddr(); ra != nil {
+				remoteAddr = ra.String()
+			}
+		}
+		el.connOpenedAt[fd] = time.Now()
+		el.eventLogger.Log(Event{
+			Type:       EventConnOpen,
+			Time:       time.Now(),
+			LoopIndex:  el.idx,
+			RemoteAddr: remoteAddr,
+			FD:         fd,
+		})
+	}
+	return
 }
@@ -0,0 +1,69 @@
+// Copyright (c) 2019 Andy Pan
+// Copyright (c) 2018 Joshua J Baker
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build linux
+
+package gnet
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// enterNetNS switches the calling OS thread into the network namespace
+// configured on opts (by path, falling back to fd) and returns a function
+// that restores the thread's original namespace. setns(2) is a per-thread
+// operation, so the caller must runtime.LockOSThread before calling this
+// and must not unlock until the returned restore func has run.
+func enterNetNS(opts *Options) (restore func() error, err error) {
+	if opts.NetNSPath == "" && opts.NetNSFd == 0 {
+		return func() error { return nil }, nil
+	}
+
+	origin, err := os.Open("/proc/self/ns/net")
+	if err != nil {
+		return nil, fmt.Errorf("gnet: failed to open current netns: %w", err)
+	}
+
+	targetFd := opts.NetNSFd
+	if opts.NetNSPath != "" {
+		var f *os.File
+		if f, err = os.Open(opts.NetNSPath); err != nil {
+			_ = origin.Close()
+			return nil, fmt.Errorf("gnet: failed to open netns %q: %w", opts.NetNSPath, err)
+		}
+		targetFd = int(f.Fd())
+		defer f.Close()
+	}
+
+	if err = unix.Setns(targetFd, unix.CLONE_NEWNET); err != nil {
+		_ = origin.Close()
+		return nil, fmt.Errorf("gnet: failed to enter netns: %w", err)
+	}
+
+	restore = func() error {
+		defer origin.Close()
+		return unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET)
+	}
+	return restore, nil
+}
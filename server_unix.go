@@ -52,6 +52,10 @@ type server struct {
 	tickerCtx    context.Context    // context for ticker
 	cancelTicker context.CancelFunc // function to stop the ticker
 	eventHandler EventHandler       // user eventHandler
+	eventLogger  EventLogger        // sink for structured lifecycle events
+
+	discoveryCtx    context.Context    // context for the etcd service-discovery goroutine
+	cancelDiscovery context.CancelFunc // function to stop service discovery
 }
 
 func (svr *server) isInShutdown() bool {
@@ -104,12 +108,43 @@ func (svr *server) startSubReactors() {
 }
 
 func (svr *server) activateEventLoops(numEventLoop int) (err error) {
+	if svr.opts.NetNSPath != "" || svr.opts.NetNSFd != 0 {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		var restore func() error
+		if restore, err = enterNetNS(svr.opts); err != nil {
+			svr.eventLogger.Log(Event{Type: EventLoopError, Time: time.Now(), Err: err})
+			return
+		}
+		defer func() { logging.LogErr(restore()) }()
+
+		// svr.ln was bound by the caller before we ever entered the target
+		// namespace, so rebind it here: otherwise only the extra ReusePort
+		// listeners created below end up inside the namespace, while the
+		// primary listener stays in the process's original one.
+		var primary *listener
+		if primary, err = initListener(svr.ln.network, svr.ln.addr, svr.opts); err != nil {
+			svr.eventLogger.Log(Event{Type: EventLoopError, Time: time.Now(), Err: err})
+			return
+		}
+		svr.ln.close()
+		svr.ln = primary
+
+		// setns is per-thread and this thread is restored on return, so
+		// only the sockets and pollers created below belong to the target
+		// namespace; the event-loop goroutines run on threads outside it.
+		// Each of them is still pinned to one OS thread for its lifetime.
+		svr.opts.LockOSThread = true
+	}
+
 	var striker *eventloop
 	// Create loops locally and bind the listeners.
 	for i := 0; i < numEventLoop; i++ {
 		l := svr.ln
 		if i > 0 && svr.opts.ReusePort {
 			if l, err = initListener(svr.ln.network, svr.ln.addr, svr.opts); err != nil {
+				svr.eventLogger.Log(Event{Type: EventLoopError, Time: time.Now(), LoopIndex: i, Err: err})
 				return
 			}
 		}
@@ -122,7 +157,9 @@ func (svr *server) activateEventLoops(numEventLoop int) (err error) {
 			el.poller = p
 			el.buffer = make([]byte, svr.opts.ReadBufferCap)
 			el.connections = make(map[int]*conn)
+			el.connOpenedAt = make(map[int]time.Time)
 			el.eventHandler = svr.eventHandler
+			el.eventLogger = svr.eventLogger
 			_ = el.poller.AddRead(el.ln.fd)
 			svr.lb.register(el)
 
@@ -131,6 +168,7 @@ func (svr *server) activateEventLoops(numEventLoop int) (err error) {
 				striker = el
 			}
 		} else {
+			svr.eventLogger.Log(Event{Type: EventLoopError, Time: time.Now(), LoopIndex: i, Err: err})
 			return
 		}
 	}
@@ -144,6 +182,32 @@ func (svr *server) activateEventLoops(numEventLoop int) (err error) {
 }
 
 func (svr *server) activateReactors(numEventLoop int) error {
+	if svr.opts.NetNSPath != "" || svr.opts.NetNSFd != 0 {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		restore, err := enterNetNS(svr.opts)
+		if err != nil {
+			svr.eventLogger.Log(Event{Type: EventLoopError, Time: time.Now(), Err: err})
+			return err
+		}
+		defer func() { logging.LogErr(restore()) }()
+
+		// Same as activateEventLoops: svr.ln was bound before we entered
+		// the namespace, so it has to be rebound here. This is also the
+		// common non-ReusePort path, so without this the feature would be
+		// a no-op for the majority of servers.
+		primary, err := initListener(svr.ln.network, svr.ln.addr, svr.opts)
+		if err != nil {
+			svr.eventLogger.Log(Event{Type: EventLoopError, Time: time.Now(), Err: err})
+			return err
+		}
+		svr.ln.close()
+		svr.ln = primary
+
+		svr.opts.LockOSThread = true
+	}
+
 	for i := 0; i < numEventLoop; i++ {
 		if p, err := netpoll.OpenPoller(); err == nil {
 			el := new(eventloop)
@@ -152,9 +216,12 @@ func (svr *server) activateReactors(numEventLoop int) error {
 			el.poller = p
 			el.buffer = make([]byte, svr.opts.ReadBufferCap)
 			el.connections = make(map[int]*conn)
+			el.connOpenedAt = make(map[int]time.Time)
 			el.eventHandler = svr.eventHandler
+			el.eventLogger = svr.eventLogger
 			svr.lb.register(el)
 		} else {
+			svr.eventLogger.Log(Event{Type: EventLoopError, Time: time.Now(), LoopIndex: i, Err: err})
 			return err
 		}
 	}
@@ -169,6 +236,7 @@ func (svr *server) activateReactors(numEventLoop int) error {
 		el.svr = svr
 		el.poller = p
 		el.eventHandler = svr.eventHandler
+		el.eventLogger = svr.eventLogger
 		_ = el.poller.AddRead(el.ln.fd)
 		svr.mainLoop = el
 
@@ -179,6 +247,7 @@ func (svr *server) activateReactors(numEventLoop int) error {
 			svr.wg.Done()
 		}()
 	} else {
+		svr.eventLogger.Log(Event{Type: EventLoopError, Time: time.Now(), LoopIndex: -1, Err: err})
 		return err
 	}
 
@@ -204,6 +273,8 @@ func (svr *server) stop(s Server) {
 
 	svr.eventHandler.OnShutdown(s)
 
+	svr.eventLogger.Log(Event{Type: EventServerShutdown, Time: time.Now()})
+
 	// Notify all loops to close by closing all listeners
 	svr.lb.iterate(func(i int, el *eventloop) bool {
 		logging.LogErr(el.poller.Trigger(func() error {
@@ -233,6 +304,13 @@ func (svr *server) stop(s Server) {
 		svr.cancelTicker()
 	}
 
+	// Stop renewing the etcd lease, if service discovery was enabled.
+	if svr.cancelDiscovery != nil {
+		svr.cancelDiscovery()
+	}
+
+	logging.LogErr(svr.eventLogger.Close())
+
 	atomic.StoreInt32(&svr.inShutdown, 1)
 }
 
@@ -270,6 +348,12 @@ func serve(eventHandler EventHandler, listener *listener, options *Options, prot
 		}
 		return options.Codec
 	}()
+	svr.eventLogger = func() EventLogger {
+		if options.EventLogger == nil {
+			return NullEventLogger{}
+		}
+		return options.EventLogger
+	}()
 
 	server := Server{
 		svr:          svr,
@@ -292,6 +376,13 @@ func serve(eventHandler EventHandler, listener *listener, options *Options, prot
 	}
 	defer svr.stop(server)
 
+	svr.eventLogger.Log(Event{Type: EventServerStarted, Time: time.Now()})
+
+	if len(options.EtcdEndpoints) > 0 {
+		svr.discoveryCtx, svr.cancelDiscovery = context.WithCancel(context.Background())
+		go svr.startServiceDiscovery(options, listener, numEventLoop)
+	}
+
 	allServers.Store(protoAddr, svr)
 
 	return nil